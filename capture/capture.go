@@ -0,0 +1,239 @@
+// Package capture records HTTP traffic into a bounded, in-memory ring
+// buffer for later inspection — useful for support/debugging in
+// production, where reproducing a request as a curl command beats asking
+// a caller to paste logs.
+//
+// Plug a Service into a client's OnRequest/OnResponse hook chain:
+//
+//	svc := capture.New(200, 64*1024)
+//	client := httpclient.New(logger,
+//		httpclient.AddOnRequest(svc.OnRequest),
+//		httpclient.AddOnResponse(svc.OnResponse),
+//	)
+package capture
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Service is a bounded ring buffer of captured Entry values. It is safe
+// for concurrent use.
+type Service struct {
+	mu           sync.Mutex
+	capacity     int
+	maxBodyBytes int
+	entries      []*Entry
+	nextID       uint64
+}
+
+// New creates a Service that retains at most `capacity` entries, each with
+// request/response bodies truncated to `maxBodyBytes` (0 means
+// unlimited).
+func New(capacity, maxBodyBytes int) *Service {
+	return &Service{
+		capacity:     capacity,
+		maxBodyBytes: maxBodyBytes,
+	}
+}
+
+// OnRequest is an httpclient.AddOnRequest-compatible hook that starts
+// capturing an entry for req.
+//
+// Neither the body nor the headers are snapshotted here: OnRequest hooks
+// run before per-call RequestOptions (see Client's hook-ordering
+// guarantee), so the request is not yet in its final state — a body
+// installed by a RequestOption such as SetForm or SetMultipart hasn't
+// been set yet, and headers may still change. OnResponse captures both
+// once the request is fully built.
+func (s *Service) OnRequest(ctx context.Context, req *http.Request) error {
+	entry := &Entry{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		begin:  time.Now(),
+	}
+
+	*req = *req.WithContext(withEntry(req.Context(), entry))
+
+	return nil
+}
+
+// OnResponse is an httpclient.AddOnResponse-compatible hook that finishes
+// the entry started by OnRequest and stores it. By the time OnResponse
+// runs, req.Body has already been fully read (and closed) by the
+// transport to send the request, so the request body is captured via
+// req.GetBody instead, which yields a fresh reader over the request body;
+// requests without a GetBody (a body that doesn't support replay) simply
+// capture no request body.
+//
+// Calling GetBody re-runs whatever produced the body (e.g. SetMultipart
+// re-reads every file). That's a deliberate, best-effort trade-off for
+// this diagnostic feature: a failure capturing the request body is
+// swallowed rather than failing an HTTP exchange that already succeeded.
+//
+// The response body is read and restored so it is still readable by the
+// caller.
+func (s *Service) OnResponse(ctx context.Context, req *http.Request, resp *http.Response) error {
+	entry, ok := entryFrom(req.Context())
+	if !ok {
+		return nil
+	}
+
+	entry.RequestHeader = req.Header.Clone()
+
+	if req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			if requestBody, err := readLimited(rc, s.maxBodyBytes); err == nil {
+				entry.RequestBody = requestBody
+			}
+		}
+	}
+
+	body, err := readAndRestore(&resp.Body, s.maxBodyBytes)
+	if err != nil {
+		return err
+	}
+
+	entry.StatusCode = resp.StatusCode
+	entry.ResponseHeader = resp.Header.Clone()
+	entry.ResponseBody = body
+	entry.Elapsed = time.Since(entry.begin)
+
+	s.store(entry)
+
+	return nil
+}
+
+// List returns a snapshot of the currently retained entries, oldest first.
+func (s *Service) List() []*Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]*Entry, len(s.entries))
+	copy(out, s.entries)
+	return out
+}
+
+// Get returns the entry with the given id, if it is still retained.
+func (s *Service) Get(id uint64) (*Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range s.entries {
+		if entry.ID == id {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Service) store(entry *Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	entry.ID = s.nextID
+
+	s.entries = append(s.entries, entry)
+	if len(s.entries) > s.capacity {
+		s.entries = s.entries[len(s.entries)-s.capacity:]
+	}
+}
+
+// readAndRestore reads *body (if any), closes it, and replaces it with a
+// fresh reader over the same bytes so downstream code still sees the
+// original content. When limit > 0, only limit+1 bytes are ever read into
+// memory by this function: the rest of the body is restored as a live
+// pass-through over the still-open underlying reader, so a capture hook
+// doesn't defeat DoStream/DownloadFile by buffering multi-megabyte bodies
+// just to throw most of them away. The returned slice is truncated to
+// limit bytes when limit > 0.
+func readAndRestore(body *io.ReadCloser, limit int) ([]byte, error) {
+	if body == nil || *body == nil {
+		return nil, nil
+	}
+
+	orig := *body
+
+	if limit <= 0 {
+		data, err := ioutil.ReadAll(orig)
+		// nolint: errcheck
+		orig.Close()
+		if err != nil {
+			*body = ioutil.NopCloser(bytes.NewReader(nil))
+			return nil, err
+		}
+
+		*body = ioutil.NopCloser(bytes.NewReader(data))
+		return data, nil
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(orig, int64(limit)+1))
+	if err != nil {
+		// nolint: errcheck
+		orig.Close()
+		*body = ioutil.NopCloser(bytes.NewReader(nil))
+		return nil, err
+	}
+
+	*body = &restoredBody{
+		Reader: io.MultiReader(bytes.NewReader(data), orig),
+		closer: orig,
+	}
+
+	if len(data) > limit {
+		return data[:limit], nil
+	}
+	return data, nil
+}
+
+// restoredBody stitches the bytes readAndRestore already consumed back
+// together with the still-open original reader, while delegating Close to
+// the original so the underlying connection/file is still released
+// properly.
+type restoredBody struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (b *restoredBody) Close() error {
+	return b.closer.Close()
+}
+
+// readLimited reads at most limit+1 bytes from rc (or everything when
+// limit <= 0), closes rc, and returns the result truncated to limit
+// bytes. Unlike readAndRestore, the caller has no further use for rc —
+// there is nothing to restore a reader onto.
+func readLimited(rc io.ReadCloser, limit int) ([]byte, error) {
+	defer rc.Close()
+
+	if limit <= 0 {
+		return ioutil.ReadAll(rc)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(rc, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > limit {
+		return data[:limit], nil
+	}
+	return data, nil
+}
+
+type entryContextKey struct{}
+
+func withEntry(ctx context.Context, entry *Entry) context.Context {
+	return context.WithValue(ctx, entryContextKey{}, entry)
+}
+
+func entryFrom(ctx context.Context) (*Entry, bool) {
+	entry, ok := ctx.Value(entryContextKey{}).(*Entry)
+	return entry, ok
+}
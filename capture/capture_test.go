@@ -0,0 +1,217 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stn81/httpclient"
+	"go.uber.org/zap"
+)
+
+func TestService_CapturesRoundTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != "hello=world" {
+			t.Errorf("server got body %q, want %q", body, "hello=world")
+		}
+		w.Header().Set("X-Test", "yes")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	svc := New(10, 0)
+	client := httpclient.New(zap.NewNop(),
+		httpclient.AddOnRequest(svc.OnRequest),
+		httpclient.AddOnResponse(svc.OnResponse),
+	)
+
+	result, err := client.Post(context.Background(), server.URL, "hello=world", httpclient.SetTypeForm())
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if result != `{"ok":true}` {
+		t.Fatalf("result = %q", result)
+	}
+
+	entries := svc.List()
+	if len(entries) != 1 {
+		t.Fatalf("len(entries) = %d, want 1", len(entries))
+	}
+
+	entry := entries[0]
+	if entry.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode = %d, want 200", entry.StatusCode)
+	}
+	if string(entry.RequestBody) != "hello=world" {
+		t.Errorf("RequestBody = %q", entry.RequestBody)
+	}
+	if string(entry.ResponseBody) != `{"ok":true}` {
+		t.Errorf("ResponseBody = %q", entry.ResponseBody)
+	}
+
+	if got, ok := svc.Get(entry.ID); !ok || got != entry {
+		t.Errorf("Get(%d) = %v, %v", entry.ID, got, ok)
+	}
+
+	if !strings.Contains(entry.Curl(), "--data-urlencode 'hello=world'") {
+		t.Errorf("Curl() = %q, missing --data-urlencode", entry.Curl())
+	}
+
+	if !strings.Contains(entry.WireDump(), "HTTP/1.1 200 OK") {
+		t.Errorf("WireDump() missing status line: %q", entry.WireDump())
+	}
+}
+
+func TestService_CapturesBodyInstalledByRequestOption(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	svc := New(10, 0)
+	client := httpclient.New(zap.NewNop(),
+		httpclient.AddOnRequest(svc.OnRequest),
+		httpclient.AddOnResponse(svc.OnResponse),
+	)
+
+	// The body here ("") is empty; the real body is installed later by
+	// SetForm, a per-call RequestOption that runs after OnRequest.
+	_, err := client.Post(context.Background(), server.URL, "", httpclient.SetForm(url.Values{"hello": {"world"}}))
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	entries := svc.List()
+	if len(entries) != 1 {
+		t.Fatalf("len(entries) = %d, want 1", len(entries))
+	}
+	if string(entries[0].RequestBody) != "hello=world" {
+		t.Errorf("RequestBody = %q, want %q", entries[0].RequestBody, "hello=world")
+	}
+}
+
+func TestService_OnResponseToleratesGetBodyFailure(t *testing.T) {
+	svc := New(10, 0)
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/path", strings.NewReader("ignored"))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return nil, errors.New("boom")
+	}
+
+	if err := svc.OnRequest(context.Background(), req); err != nil {
+		t.Fatalf("OnRequest: %v", err)
+	}
+
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader("ok")),
+	}
+
+	if err := svc.OnResponse(req.Context(), req, resp); err != nil {
+		t.Fatalf("OnResponse: %v, want nil (a GetBody failure must not fail an otherwise successful request)", err)
+	}
+
+	entries := svc.List()
+	if len(entries) != 1 {
+		t.Fatalf("len(entries) = %d, want 1", len(entries))
+	}
+	if entries[0].RequestBody != nil {
+		t.Errorf("RequestBody = %q, want nil", entries[0].RequestBody)
+	}
+}
+
+func TestService_RingBufferEvictsOldest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	svc := New(2, 0)
+	client := httpclient.New(zap.NewNop(),
+		httpclient.AddOnRequest(svc.OnRequest),
+		httpclient.AddOnResponse(svc.OnResponse),
+	)
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.Get(context.Background(), server.URL, ""); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+	}
+
+	entries := svc.List()
+	if len(entries) != 2 {
+		t.Fatalf("len(entries) = %d, want 2", len(entries))
+	}
+	if entries[0].ID != 2 || entries[1].ID != 3 {
+		t.Errorf("entries IDs = [%d, %d], want [2, 3]", entries[0].ID, entries[1].ID)
+	}
+}
+
+func TestService_TruncatesWithoutBufferingFullBody(t *testing.T) {
+	const bodySize = 5 * 1024 * 1024
+	full := strings.Repeat("x", bodySize)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(full))
+	}))
+	defer server.Close()
+
+	svc := New(10, 16)
+	client := httpclient.New(zap.NewNop(),
+		httpclient.AddOnRequest(svc.OnRequest),
+		httpclient.AddOnResponse(svc.OnResponse),
+	)
+
+	resp, err := client.DoStream(context.Background(), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("DoStream: %v", err)
+	}
+
+	got, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ReadAll(resp.Body): %v", err)
+	}
+	if len(got) != bodySize {
+		t.Fatalf("len(restored body) = %d, want %d (restoring must not drop bytes)", len(got), bodySize)
+	}
+
+	entries := svc.List()
+	if len(entries) != 1 {
+		t.Fatalf("len(entries) = %d, want 1", len(entries))
+	}
+	if len(entries[0].ResponseBody) != 16 {
+		t.Fatalf("len(ResponseBody) = %d, want 16", len(entries[0].ResponseBody))
+	}
+}
+
+func TestEntry_CurlQuotesBasicAuth(t *testing.T) {
+	entry := &Entry{
+		Method: http.MethodGet,
+		URL:    "http://example.com/path",
+		RequestHeader: http.Header{
+			"Authorization": []string{"Basic " + basicAuthValue("alice", "s3cret")},
+		},
+	}
+
+	got := entry.Curl()
+	if !strings.Contains(got, "-u 'alice:s3cret'") {
+		t.Errorf("Curl() = %q, want -u 'alice:s3cret'", got)
+	}
+}
+
+func basicAuthValue(user, pass string) string {
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	req.SetBasicAuth(user, pass)
+	auth := req.Header.Get("Authorization")
+	return strings.TrimPrefix(auth, "Basic ")
+}
@@ -0,0 +1,142 @@
+package capture
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry is a plain-data snapshot of one request/response pair. It does not
+// reference *http.Request or *http.Response, so it stays safe to inspect
+// long after the request that produced it has completed.
+type Entry struct {
+	ID             uint64
+	Method         string
+	URL            string
+	RequestHeader  http.Header
+	RequestBody    []byte
+	StatusCode     int
+	ResponseHeader http.Header
+	ResponseBody   []byte
+	Elapsed        time.Duration
+
+	begin time.Time
+}
+
+// JSON renders the entry as an indented JSON summary.
+func (e *Entry) JSON() ([]byte, error) {
+	return json.MarshalIndent(e, "", "  ")
+}
+
+// WireDump renders the entry as an HTTP/1.1-style request/response
+// transcript, similar to `curl -v` output.
+func (e *Entry) WireDump() string {
+	var b strings.Builder
+
+	requestPath := e.URL
+	if u, err := url.Parse(e.URL); err == nil {
+		requestPath = u.RequestURI()
+	}
+
+	fmt.Fprintf(&b, "%s %s HTTP/1.1\r\n", e.Method, requestPath)
+	writeHeader(&b, e.RequestHeader)
+	b.WriteString("\r\n")
+	if len(e.RequestBody) > 0 {
+		b.Write(e.RequestBody)
+		b.WriteString("\r\n")
+	}
+
+	b.WriteString("\r\n")
+
+	fmt.Fprintf(&b, "HTTP/1.1 %d %s\r\n", e.StatusCode, http.StatusText(e.StatusCode))
+	writeHeader(&b, e.ResponseHeader)
+	b.WriteString("\r\n")
+	if len(e.ResponseBody) > 0 {
+		b.Write(e.ResponseBody)
+		b.WriteString("\r\n")
+	}
+
+	return b.String()
+}
+
+func writeHeader(b *strings.Builder, header http.Header) {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, value := range header[key] {
+			fmt.Fprintf(b, "%s: %s\r\n", key, value)
+		}
+	}
+}
+
+// Curl renders the entry's request as an equivalent curl command line.
+func (e *Entry) Curl() string {
+	var b strings.Builder
+	b.WriteString("curl -X ")
+	b.WriteString(e.Method)
+
+	keys := make([]string, 0, len(e.RequestHeader))
+	for key := range e.RequestHeader {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, value := range e.RequestHeader[key] {
+			if strings.EqualFold(key, "Authorization") {
+				if user, pass, ok := decodeBasicAuth(value); ok {
+					fmt.Fprintf(&b, " -u %s", shellQuote(user+":"+pass))
+					continue
+				}
+			}
+			fmt.Fprintf(&b, " -H %s", shellQuote(key+": "+value))
+		}
+	}
+
+	if len(e.RequestBody) > 0 {
+		if isFormEncoded(e.RequestHeader) {
+			for _, pair := range strings.Split(string(e.RequestBody), "&") {
+				if unescaped, err := url.QueryUnescape(pair); err == nil {
+					pair = unescaped
+				}
+				fmt.Fprintf(&b, " --data-urlencode %s", shellQuote(pair))
+			}
+		} else {
+			fmt.Fprintf(&b, " --data-binary %s", shellQuote(string(e.RequestBody)))
+		}
+	}
+
+	fmt.Fprintf(&b, " %s", shellQuote(e.URL))
+
+	return b.String()
+}
+
+func isFormEncoded(header http.Header) bool {
+	return strings.HasPrefix(header.Get("Content-Type"), "application/x-www-form-urlencoded")
+}
+
+func decodeBasicAuth(value string) (user, pass string, ok bool) {
+	const prefix = "Basic "
+	if !strings.HasPrefix(value, prefix) {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(value[len(prefix):])
+	if err != nil {
+		return "", "", false
+	}
+	user, pass, ok = strings.Cut(string(decoded), ":")
+	return user, pass, ok
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
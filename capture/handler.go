@@ -0,0 +1,37 @@
+package capture
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Handler returns an http.Handler exposing the captured entries as JSON: a
+// GET to "/" lists all retained entries, and "/?id=<id>" returns a single
+// entry.
+func (s *Service) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+		if id := r.URL.Query().Get("id"); id != "" {
+			n, err := strconv.ParseUint(id, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid id", http.StatusBadRequest)
+				return
+			}
+
+			entry, ok := s.Get(n)
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+
+			// nolint: errcheck
+			json.NewEncoder(w).Encode(entry)
+			return
+		}
+
+		// nolint: errcheck
+		json.NewEncoder(w).Encode(s.List())
+	})
+}
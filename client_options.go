@@ -1,8 +1,14 @@
 package httpclient
 
 import (
+	"context"
 	"net/http"
+	"net/url"
 	"time"
+
+	"github.com/eapache/go-resiliency/breaker"
+
+	"github.com/stn81/httpclient/ratelimit"
 )
 
 // ClientOption defines the client option to customize the client
@@ -42,3 +48,90 @@ func DisableTrafficDebug() ClientOption {
 		client.debugTraffic = false
 	}
 }
+
+// AddOnRequest registers one or more hooks run, in the order added, on
+// every request before it is sent. Hooks added this way run before any
+// per-call RequestOption, so cross-cutting concerns (auth, tracing) stay
+// overridable at the call site.
+func AddOnRequest(hooks ...func(context.Context, *http.Request) error) ClientOption {
+	return func(client *Client) {
+		client.onRequest = append(client.onRequest, hooks...)
+	}
+}
+
+// AddOnResponse registers one or more hooks run, in the order added, on
+// every response after it is received and before status-code handling
+// (so a hook can inspect headers on error responses too).
+func AddOnResponse(hooks ...func(context.Context, *http.Request, *http.Response) error) ClientOption {
+	return func(client *Client) {
+		client.onResponse = append(client.onResponse, hooks...)
+	}
+}
+
+// UserAgent sets the User-Agent header on every outgoing request
+func UserAgent(userAgent string) ClientOption {
+	return AddOnRequest(func(ctx context.Context, req *http.Request) error {
+		req.Header.Set("User-Agent", userAgent)
+		return nil
+	})
+}
+
+// BearerToken injects an `Authorization: Bearer <token>` header on every
+// outgoing request, calling tokenFunc lazily so the token can be refreshed
+// between requests.
+func BearerToken(tokenFunc func(ctx context.Context) (string, error)) ClientOption {
+	return AddOnRequest(func(ctx context.Context, req *http.Request) error {
+		token, err := tokenFunc(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// WithRateLimiter makes the client acquire a token from l before sending
+// each request, and feed the response's rate-limit headers back into l
+// afterward.
+func WithRateLimiter(l *ratelimit.Limiter) ClientOption {
+	return func(client *Client) {
+		client.rateLimiter = l
+	}
+}
+
+// BreakerFunc selects the circuit breaker to use for a given request host.
+// Returning nil falls back to the client's default breaker, if any (see
+// WithBreaker).
+type BreakerFunc func(host string) *breaker.Breaker
+
+// WithBreaker wraps every outgoing request in b, failing fast with a
+// *BreakerOpenError instead of sending the request while b is open. For
+// per-host breakers, use WithBreakerFunc instead.
+func WithBreaker(b *breaker.Breaker) ClientOption {
+	return func(client *Client) {
+		client.breaker = b
+	}
+}
+
+// WithBreakerFunc selects a circuit breaker per request host, so one
+// unhealthy upstream doesn't trip requests to other hosts.
+func WithBreakerFunc(breakerFunc BreakerFunc) ClientOption {
+	return func(client *Client) {
+		client.breakerFunc = breakerFunc
+	}
+}
+
+// WithBaseURL sets a base URL that every per-call URL is resolved against
+// via url.URL.ResolveReference, so callers can pass a path like
+// "/v1/things" instead of a full URL. An invalid base is recorded and
+// surfaces as an error from the client's first request.
+func WithBaseURL(base string) ClientOption {
+	return func(client *Client) {
+		u, err := url.Parse(base)
+		if err != nil {
+			client.baseURLErr = err
+			return
+		}
+		client.baseURL = u
+	}
+}
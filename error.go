@@ -1,14 +1,29 @@
 package httpclient
 
-import "fmt"
+import (
+	"fmt"
+	"net/http"
+)
 
 // HTTPError is the http error status code info, which is not in range [200,300)
 type HTTPError struct {
 	StatusCode int
 	StatusText string
+	Header     http.Header
 }
 
 // Error implements the error interface
 func (e *HTTPError) Error() string {
 	return fmt.Sprintf("HTTP Error: %v, %v", e.StatusCode, e.StatusText)
 }
+
+// BreakerOpenError is returned when a request is not attempted because a
+// circuit breaker protecting its host is currently open.
+type BreakerOpenError struct {
+	Host string
+}
+
+// Error implements the error interface
+func (e *BreakerOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open for host %q", e.Host)
+}
@@ -2,19 +2,22 @@ package httpclient
 
 import (
 	"bytes"
-	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"time"
 
 	"context"
 
+	"github.com/eapache/go-resiliency/breaker"
 	"github.com/eapache/go-resiliency/retrier"
 	"go.uber.org/zap"
+
+	"github.com/stn81/httpclient/ratelimit"
 )
 
 var (
@@ -22,13 +25,25 @@ var (
 	DefaultTimeout = 15 * time.Second
 )
 
-// Client is the http client handle
+// Client is the http client handle.
+//
+// OnRequest/OnResponse hooks (added via AddOnRequest/AddOnResponse) are
+// client-wide defaults that run on every request; they are invoked before
+// any per-call RequestOption so that a per-call option can still override
+// what a hook set.
 type Client struct {
 	*http.Client
 	retrier      *retrier.Retrier
 	reqOpts      []RequestOption
 	logger       *zap.Logger
 	debugTraffic bool
+	onRequest    []func(context.Context, *http.Request) error
+	onResponse   []func(context.Context, *http.Request, *http.Response) error
+	rateLimiter  *ratelimit.Limiter
+	breaker      *breaker.Breaker
+	breakerFunc  BreakerFunc
+	baseURL      *url.URL
+	baseURLErr   error
 }
 
 // New creates a new http client with specified client options
@@ -59,6 +74,28 @@ func (client *Client) SetDefaultReqOpts(reqOpts ...RequestOption) {
 	client.reqOpts = reqOpts[:len(reqOpts):len(reqOpts)]
 }
 
+// Clone returns a copy of client that shares the same underlying
+// *http.Client (transport, cookie jar, timeout) and retrier, but has its
+// own default RequestOptions and hook slices, so mutating the clone (e.g.
+// via SetDefaultReqOpts) never affects client.
+func (client *Client) Clone() *Client {
+	clone := *client
+	clone.reqOpts = append([]RequestOption(nil), client.reqOpts...)
+	clone.onRequest = append([]func(context.Context, *http.Request) error(nil), client.onRequest...)
+	clone.onResponse = append([]func(context.Context, *http.Request, *http.Response) error(nil), client.onResponse...)
+	return &clone
+}
+
+// WithContextDefaults returns a Clone of client with reqOpts appended to
+// its default request options, for building a scoped sub-client (e.g. an
+// authenticated PostsService layered on a shared transport) without
+// mutating the client it was built from.
+func (client *Client) WithContextDefaults(reqOpts ...RequestOption) *Client {
+	clone := client.Clone()
+	clone.reqOpts = append(clone.reqOpts, reqOpts...)
+	return clone
+}
+
 // SetRetry set the retry backoff
 func (client *Client) SetRetry(backoff []time.Duration) {
 	client.retrier = retrier.New(backoff, DefaultRetryClassifier)
@@ -69,6 +106,13 @@ func (client *Client) SetRetrier(r *retrier.Retrier) {
 	client.retrier = r
 }
 
+// SetBreaker sets a circuit breaker that wraps every outgoing request,
+// failing fast with a *BreakerOpenError while it is open. See
+// WithBreakerFunc for per-host breakers.
+func (client *Client) SetBreaker(b *breaker.Breaker) {
+	client.breaker = b
+}
+
 // Options sends the OPTIONS request
 func (client *Client) Options(ctx context.Context, url, body string, reqOpts ...RequestOption) (result string, err error) {
 	return client.Do(ctx, "OPTIONS", url, body, reqOpts...)
@@ -104,68 +148,84 @@ func (client *Client) Delete(ctx context.Context, url, body string, reqOpts ...R
 	return client.Do(ctx, "DELETE", url, body, reqOpts...)
 }
 
-// Do sends a custom METHOD request
+// Do sends a custom METHOD request, buffering the whole response body into
+// a string. It is a thin wrapper around DoStream kept for backward
+// compatibility; prefer DoStream for large or streamed payloads.
 func (client *Client) Do(ctx context.Context, method, url, body string, reqOpts ...RequestOption) (result string, err error) {
-	if client.retrier == nil {
-		return client.do(ctx, method, url, body, reqOpts...)
-	}
+	err = client.runRetriable(ctx, func() error {
+		begin := time.Now()
+
+		resp, doErr := client.doStream(ctx, method, url, strings.NewReader(body), reqOpts...)
+		if doErr != nil {
+			return doErr
+		}
+
+		data, readErr := resp.Bytes()
+		if readErr != nil {
+			client.logger.Error("read response body", zap.Error(readErr), zap.Duration("proc_time", time.Since(begin)))
+			return wrapRequestError(readErr, method, resp.allowNonIdempotent)
+		}
+		result = string(data)
 
-	err = client.retrier.Run(func() error {
-		if result, err = client.do(ctx, method, url, body, reqOpts...); err != nil {
-			return err
+		buf := &bytes.Buffer{}
+		for _, cookie := range resp.Cookies() {
+			buf.WriteString(fmt.Sprintf("%v=%v|", cookie.Name, cookie.Value))
 		}
+		if buf.Len() > 0 {
+			buf.Truncate(buf.Len() - 1)
+		}
+
+		logger := client.logger.With(
+			zap.String("method", method),
+			zap.String("url", resp.Request.URL.String()),
+		)
+		if client.debugTraffic {
+			logger.Debug("request success",
+				zap.String("result", result),
+				zap.String("set_cookies", buf.String()),
+				zap.Duration("proc_time", time.Since(begin)),
+			)
+		} else {
+			logger.Debug("request success",
+				zap.String("set_cookies", buf.String()),
+				zap.Duration("proc_time", time.Since(begin)),
+			)
+		}
+
 		return nil
 	})
 
 	return result, err
 }
 
+// DoStream sends a request and returns the raw Response for the caller to
+// stream from; the body is already unwrapped from a gzip, deflate or br
+// Content-Encoding the server applied (any other encoding is passed through
+// as is). The caller must close Response.Body.
+//
+// DoStream makes a single attempt regardless of the client's retrier:
+// once bytes start flowing to the caller there is no generally safe way to
+// replay a partially consumed stream.
+func (client *Client) DoStream(ctx context.Context, method, url string, body io.Reader, reqOpts ...RequestOption) (*Response, error) {
+	return client.doStream(ctx, method, url, body, reqOpts...)
+}
+
 // DownloadFile download file from url
 func (client *Client) DownloadFile(ctx context.Context, url, outFile string, reqOpts ...RequestOption) (err error) {
-	var (
-		req    *http.Request
-		resp   *http.Response
-		method = "GET"
-	)
+	begin := time.Now()
 
-	if req, err = http.NewRequest(method, url, nil); err != nil {
+	resp, err := client.doStream(ctx, "GET", url, nil, reqOpts...)
+	if err != nil {
 		return err
 	}
-
-	reqOpts = append(client.reqOpts, reqOpts...)
-
-	for _, reqOpt := range reqOpts {
-		if ctx, err = reqOpt(ctx, req); err != nil {
-			return err
-		}
-	}
-
-	if client.Timeout == 0 {
-		client.Timeout = DefaultTimeout
-	}
+	defer resp.Body.Close()
 
 	logger := client.logger.With(
-		zap.String("method", method),
-		zap.String("url", req.URL.String()),
+		zap.String("method", "GET"),
+		zap.String("url", resp.Request.URL.String()),
 		zap.String("out_file", outFile),
 	)
 
-	begin := time.Now()
-	resp, err = client.Client.Do(req)
-	if err != nil {
-		logger.Error("do http request", zap.Error(err), zap.Duration("proc_time", time.Since(begin)))
-		return err
-	}
-	// nolint: errcheck
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		err = &HTTPError{resp.StatusCode, resp.Status}
-		logger.Error("bad http status code", zap.Error(err), zap.Duration("proc_time", time.Since(begin)))
-		return err
-	}
-
-	// open file
 	out, err := os.Create(outFile)
 	if err != nil {
 		logger.Error("create download file", zap.Error(err), zap.Duration("proc_time", time.Since(begin)))
@@ -182,29 +242,101 @@ func (client *Client) DownloadFile(ctx context.Context, url, outFile string, req
 	logger.Debug("request success", zap.Int64("file_size", written), zap.Duration("proc_time", time.Since(begin)))
 
 	return nil
+}
+
+// runRetriable executes attempt, retrying according to the client's
+// retrier (if any). When a failed attempt surfaces a Retry-After hint and
+// no rate limiter is already handling that wait, runRetriable sleeps for
+// it before the retrier's next attempt.
+func (client *Client) runRetriable(ctx context.Context, attempt func() error) error {
+	if client.retrier == nil {
+		return attempt()
+	}
 
+	return client.retrier.Run(func() error {
+		err := attempt()
+		if err != nil && client.rateLimiter == nil {
+			if wait, ok := RetryAfter(err); ok && wait > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(wait):
+				}
+			}
+		}
+		return err
+	})
 }
 
-// do the internal request sending implementation
-func (client *Client) do(ctx context.Context, method, url, body string, reqOpts ...RequestOption) (result string, err error) {
-	var (
-		req      *http.Request
-		resp     *http.Response
-		respData []byte
-	)
+// breakerFor returns the circuit breaker that should guard requests to
+// host, preferring a per-host breaker from breakerFunc and falling back to
+// the client's default breaker.
+func (client *Client) breakerFor(host string) *breaker.Breaker {
+	if client.breakerFunc != nil {
+		if b := client.breakerFunc(host); b != nil {
+			return b
+		}
+	}
+	return client.breaker
+}
+
+// resolveURL resolves rawURL against the client's base URL (set via
+// WithBaseURL), so callers can pass a path like "/v1/things" instead of a
+// full URL. With no base URL configured, rawURL is returned unchanged.
+func (client *Client) resolveURL(rawURL string) (string, error) {
+	if client.baseURLErr != nil {
+		return "", client.baseURLErr
+	}
+	if client.baseURL == nil {
+		return rawURL, nil
+	}
 
-	if req, err = http.NewRequest(method, url, strings.NewReader(body)); err != nil {
+	ref, err := url.Parse(rawURL)
+	if err != nil {
 		return "", err
 	}
 
+	return client.baseURL.ResolveReference(ref).String(), nil
+}
+
+// doStream is the shared low-level implementation behind DoStream,
+// DownloadFile, and Do: it builds the request, runs it through the
+// OnRequest/OnResponse hook chain and rate limiter, and returns a Response
+// whose Body is already unwrapped from a gzip, deflate or br
+// Content-Encoding.
+func (client *Client) doStream(ctx context.Context, method, rawURL string, body io.Reader, reqOpts ...RequestOption) (response *Response, err error) {
+	resolvedURL, err := client.resolveURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var req *http.Request
+
+	if req, err = http.NewRequest(method, resolvedURL, body); err != nil {
+		return nil, err
+	}
+
+	for _, hook := range client.onRequest {
+		if err = hook(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+
 	reqOpts = append(client.reqOpts, reqOpts...)
 
 	for _, reqOpt := range reqOpts {
 		if ctx, err = reqOpt(ctx, req); err != nil {
-			return "", err
+			return nil, err
 		}
 	}
 
+	allowNonIdempotent, _ := ctx.Value(allowRetryNonIdempotentKey).(bool)
+	defer func() {
+		if err != nil {
+			err = &requestError{err: err, method: method, allowNonIdempotent: allowNonIdempotent}
+		}
+	}()
+
 	if client.Timeout == 0 {
 		client.Timeout = DefaultTimeout
 	}
@@ -213,67 +345,85 @@ func (client *Client) do(ctx context.Context, method, url, body string, reqOpts
 		zap.String("method", method),
 		zap.String("url", req.URL.String()),
 	)
-	if client.debugTraffic {
-		logger = logger.With(zap.String("body", body))
+
+	if client.rateLimiter != nil {
+		if err = client.rateLimiter.Acquire(ctx, req); err != nil {
+			return nil, err
+		}
 	}
 
 	begin := time.Now()
-	resp, err = client.Client.Do(req)
-	if err != nil {
-		logger.Error("do http request", zap.Error(err), zap.Duration("proc_time", time.Since(begin)))
-		return "", err
-	}
-	// nolint: errcheck
-	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		err = &HTTPError{resp.StatusCode, resp.Status}
-		logger.Error("bad http status code", zap.Error(err), zap.Duration("proc_time", time.Since(begin)))
-		return "", err
-	}
+	var resp *http.Response
+	var statusErr error
+
+	// attempt is the unit of work a circuit breaker tracks: it covers the
+	// round trip and the response hooks, plus any 5xx status, since those
+	// indicate the upstream itself is unhealthy. Ordinary 4xx responses are
+	// still surfaced to the caller as an error (via statusErr below) but
+	// are not reported to the breaker, since a client making routine bad
+	// requests (typos, "does X exist" probes) shouldn't trip the circuit
+	// for every other caller of a perfectly healthy host.
+	attempt := func() error {
+		var doErr error
+		resp, doErr = client.Client.Do(req)
+		if client.rateLimiter != nil {
+			// nolint: errcheck
+			client.rateLimiter.Release(ctx, req, resp)
+		}
+		if doErr != nil {
+			logger.Error("do http request", zap.Error(doErr), zap.Duration("proc_time", time.Since(begin)))
+			return doErr
+		}
 
-	var reader io.ReadCloser
-	// for the case server send gzipped data even if client not sending "Accept-Encoding: gzip"
-	switch resp.Header.Get("Content-Encoding") {
-	case "gzip":
-		if reader, err = gzip.NewReader(resp.Body); err != nil {
-			logger.Error("create gzip reader", zap.Error(err), zap.Duration("proc_time", time.Since(begin)))
-			return "", err
+		for _, hook := range client.onResponse {
+			if hookErr := hook(ctx, req, resp); hookErr != nil {
+				// nolint: errcheck
+				resp.Body.Close()
+				logger.Error("on response hook", zap.Error(hookErr), zap.Duration("proc_time", time.Since(begin)))
+				return hookErr
+			}
 		}
-		defer reader.Close()
-	default:
-		reader = ioutil.NopCloser(resp.Body)
-	}
 
-	if respData, err = ioutil.ReadAll(reader); err != nil {
-		logger.Error("read response body", zap.Error(err), zap.Duration("proc_time", time.Since(begin)))
-		return "", err
-	}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			statusErr = &HTTPError{resp.StatusCode, resp.Status, resp.Header}
+			// nolint: errcheck
+			resp.Body.Close()
+			logger.Error("bad http status code", zap.Error(statusErr), zap.Duration("proc_time", time.Since(begin)))
+			if resp.StatusCode >= 500 {
+				return statusErr
+			}
+			return nil
+		}
 
-	result = string(respData)
+		return nil
+	}
 
-	buf := &bytes.Buffer{}
-	for _, cookie := range resp.Cookies() {
-		buf.WriteString(fmt.Sprintf("%v=%v|", cookie.Name, cookie.Value))
+	if b := client.breakerFor(req.URL.Host); b != nil {
+		err = b.Run(attempt)
+		if errors.Is(err, breaker.ErrBreakerOpen) {
+			err = &BreakerOpenError{Host: req.URL.Host}
+			logger.Error("circuit breaker open", zap.Error(err))
+		}
+	} else {
+		err = attempt()
 	}
 
-	if buf.Len() > 0 {
-		buf.Truncate(buf.Len() - 1)
+	if err == nil && statusErr != nil {
+		err = statusErr
 	}
 
-	if client.debugTraffic {
-		logger.Debug("request success",
-			zap.String("result", result),
-			zap.String("set_cookies", buf.String()),
-			zap.Duration("proc_time", time.Since(begin)),
-		)
-	} else {
-		logger.Debug("request success",
-			zap.String("set_cookies", buf.String()),
-			zap.Duration("proc_time", time.Since(begin)),
-		)
+	if err != nil {
+		return nil, err
+	}
 
+	decodedBody, err := decodeBody(resp)
+	if err != nil {
+		// nolint: errcheck
+		resp.Body.Close()
+		logger.Error("create decompress reader", zap.Error(err), zap.Duration("proc_time", time.Since(begin)))
+		return nil, err
 	}
 
-	return result, nil
+	return &Response{Response: resp, Body: decodedBody, allowNonIdempotent: allowNonIdempotent}, nil
 }
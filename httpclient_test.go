@@ -0,0 +1,226 @@
+package httpclient
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eapache/go-resiliency/breaker"
+	"go.uber.org/zap"
+)
+
+func TestClient_DoStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusOK)
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(`{"hello":"world"}`))
+		gz.Close()
+	}))
+	defer server.Close()
+
+	client := New(zap.NewNop())
+
+	resp, err := client.DoStream(context.Background(), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("DoStream: %v", err)
+	}
+
+	var v struct {
+		Hello string `json:"hello"`
+	}
+	if err := resp.JSON(&v); err != nil {
+		t.Fatalf("Response.JSON: %v", err)
+	}
+	if v.Hello != "world" {
+		t.Fatalf("v.Hello = %q, want %q", v.Hello, "world")
+	}
+}
+
+func TestClient_DoBackwardCompat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := New(zap.NewNop())
+
+	result, err := client.Get(context.Background(), server.URL, "")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if result != "ok" {
+		t.Fatalf("result = %q, want %q", result, "ok")
+	}
+}
+
+func TestClient_BreakerOpensAndFailsFast(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := New(zap.NewNop(), WithBreaker(breaker.New(1, 1, time.Minute)))
+
+	if _, err := client.Get(context.Background(), server.URL, ""); err == nil {
+		t.Fatal("Get: want error for 503, got nil")
+	}
+
+	_, err := client.Get(context.Background(), server.URL, "")
+	var breakerErr *BreakerOpenError
+	if !errors.As(err, &breakerErr) {
+		t.Fatalf("Get: err = %v, want *BreakerOpenError", err)
+	}
+	if requests != 1 {
+		t.Fatalf("requests = %d, want 1 (breaker should fail fast on second call)", requests)
+	}
+}
+
+func TestClient_BreakerIgnoresClientErrors(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := New(zap.NewNop(), WithBreaker(breaker.New(1, 1, time.Minute)))
+
+	for i := 0; i < 3; i++ {
+		_, err := client.Get(context.Background(), server.URL, "")
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Fatalf("Get #%d: err = %v, want *HTTPError", i, err)
+		}
+	}
+
+	if requests != 3 {
+		t.Fatalf("requests = %d, want 3 (404s must not trip the breaker)", requests)
+	}
+}
+
+func TestClient_DoDoesNotRetryNonIdempotentOnTruncatedBody(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// Promise more bytes than we write, then cut the connection, so
+		// reading the body fails with io.ErrUnexpectedEOF even though the
+		// POST already ran server-side.
+		w.Header().Set("Content-Length", "100")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("short"))
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			t.Fatal("ResponseWriter does not support hijacking")
+		}
+		conn, _, err := hijacker.Hijack()
+		if err != nil {
+			t.Fatalf("Hijack: %v", err)
+		}
+		conn.Close()
+	}))
+	defer server.Close()
+
+	client := New(zap.NewNop())
+	client.SetRetry([]time.Duration{0, 0})
+
+	if _, err := client.Post(context.Background(), server.URL, ""); err == nil {
+		t.Fatal("Post: want error for truncated body, got nil")
+	}
+	if requests != 1 {
+		t.Fatalf("requests = %d, want 1 (non-idempotent POST must not be retried after a body-read failure)", requests)
+	}
+}
+
+func TestClient_DoRetriesNonIdempotentOnTruncatedBodyWhenOptedIn(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 2 {
+			w.Header().Set("Content-Length", "100")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("short"))
+			hijacker, ok := w.(http.Hijacker)
+			if !ok {
+				t.Fatal("ResponseWriter does not support hijacking")
+			}
+			conn, _, err := hijacker.Hijack()
+			if err != nil {
+				t.Fatalf("Hijack: %v", err)
+			}
+			conn.Close()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := New(zap.NewNop())
+	client.SetRetry([]time.Duration{0, 0})
+
+	result, err := client.Post(context.Background(), server.URL, "", AllowRetryNonIdempotent())
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if result != "ok" {
+		t.Fatalf("result = %q, want %q", result, "ok")
+	}
+	if requests != 2 {
+		t.Fatalf("requests = %d, want 2 (AllowRetryNonIdempotent must be honored after a body-read failure)", requests)
+	}
+}
+
+func TestClient_BaseURLResolvesPaths(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := New(zap.NewNop(), WithBaseURL(server.URL+"/v1/"))
+
+	result, err := client.Get(context.Background(), "things", "")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if result != "ok" {
+		t.Fatalf("result = %q, want %q", result, "ok")
+	}
+	if gotPath != "/v1/things" {
+		t.Fatalf("path = %q, want %q", gotPath, "/v1/things")
+	}
+}
+
+func TestClient_WithContextDefaultsDoesNotMutateParent(t *testing.T) {
+	var gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("X-Scoped")
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	parent := New(zap.NewNop())
+	scoped := parent.WithContextDefaults(SetHeader("X-Scoped", "yes"))
+
+	if _, err := scoped.Get(context.Background(), server.URL, ""); err != nil {
+		t.Fatalf("scoped Get: %v", err)
+	}
+	if gotHeader != "yes" {
+		t.Fatalf("scoped request header = %q, want %q", gotHeader, "yes")
+	}
+
+	if _, err := parent.Get(context.Background(), server.URL, ""); err != nil {
+		t.Fatalf("parent Get: %v", err)
+	}
+	if gotHeader != "" {
+		t.Fatalf("parent request header = %q, want empty", gotHeader)
+	}
+}
@@ -1,8 +1,11 @@
 package httpclient
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"io"
+	"io/ioutil"
 
 	"go.uber.org/zap"
 )
@@ -53,13 +56,10 @@ func (client *JSONClient) Delete(ctx context.Context, url string, body, result i
 	return client.Do(ctx, "DELETE", url, body, result, reqOpts...)
 }
 
-// Do sends a custom METHOD request
+// Do sends a custom METHOD request, decoding the response body as JSON
+// incrementally via DoStream instead of buffering it into a string first.
 func (client *JSONClient) Do(ctx context.Context, method, url string, body, result interface{}, reqOpts ...RequestOption) error {
-	var (
-		bodyData  []byte
-		resultStr string
-		err       error
-	)
+	var bodyData []byte
 
 	if body != nil {
 		switch bodyValue := body.(type) {
@@ -70,6 +70,7 @@ func (client *JSONClient) Do(ctx context.Context, method, url string, body, resu
 		case []byte:
 			bodyData = bodyValue
 		default:
+			var err error
 			if bodyData, err = json.Marshal(body); err != nil {
 				client.logger.Error("marshal request body", zap.Error(err))
 				return err
@@ -79,15 +80,25 @@ func (client *JSONClient) Do(ctx context.Context, method, url string, body, resu
 
 	reqOpts = append([]RequestOption{SetTypeJSON()}, reqOpts...)
 
-	if resultStr, err = client.Client.Do(ctx, method, url, string(bodyData), reqOpts...); err != nil {
-		return err
-	}
+	return client.runRetriable(ctx, func() error {
+		resp, err := client.Client.DoStream(ctx, method, url, bytes.NewReader(bodyData), reqOpts...)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if result == nil {
+			_, err := io.Copy(ioutil.Discard, resp.Body)
+			return wrapRequestError(err, method, resp.allowNonIdempotent)
+		}
 
-	if result != nil && resultStr != "" {
-		if err = json.Unmarshal([]byte(resultStr), result); err != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			if err == io.EOF {
+				return nil
+			}
 			client.logger.Error("unmarshal response body", zap.Error(err))
-			return err
+			return wrapRequestError(err, method, resp.allowNonIdempotent)
 		}
-	}
-	return nil
+		return nil
+	})
 }
@@ -0,0 +1,222 @@
+// Package ratelimit implements a per-host token-bucket limiter that can be
+// wired into an httpclient.Client via httpclient.WithRateLimiter, and that
+// adjusts itself from the rate-limit headers a server returns.
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type bucketKeyContextKey struct{}
+
+// WithBucketKey overrides the bucket key a Limiter would otherwise derive
+// from the request's host. Use this when several hosts share a quota, or a
+// single host exposes several independently limited buckets.
+func WithBucketKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, bucketKeyContextKey{}, key)
+}
+
+// Limiter is a per-host token-bucket rate limiter. Buckets are created
+// lazily on first use and refill at the configured rate up to capacity.
+type Limiter struct {
+	capacity int
+	refill   time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// New creates a Limiter that allows `capacity` requests per bucket, and
+// refills one token every `refill` interval, up to capacity.
+func New(capacity int, refill time.Duration) *Limiter {
+	return &Limiter{
+		capacity: capacity,
+		refill:   refill,
+		buckets:  make(map[string]*bucket),
+	}
+}
+
+// Acquire blocks, respecting ctx, until a token is available for the
+// bucket keyed by req.URL.Host (or the key set via WithBucketKey).
+func (l *Limiter) Acquire(ctx context.Context, req *http.Request) error {
+	return l.bucketFor(ctx, req).acquire(ctx, l.capacity, l.refill)
+}
+
+// Release inspects the response's rate-limit headers and adjusts the
+// bucket's token count and next-available time accordingly.
+func (l *Limiter) Release(ctx context.Context, req *http.Request, resp *http.Response) error {
+	l.bucketFor(ctx, req).adjust(resp)
+	return nil
+}
+
+func (l *Limiter) bucketFor(ctx context.Context, req *http.Request) *bucket {
+	key := req.URL.Host
+	if override, ok := ctx.Value(bucketKeyContextKey{}).(string); ok && override != "" {
+		key = override
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.capacity, lastRefill: time.Now()}
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// bucket tracks the available tokens and earliest-next-request time for a
+// single rate-limit key.
+type bucket struct {
+	mu            sync.Mutex
+	tokens        int
+	lastRefill    time.Time
+	nextAvailable time.Time
+}
+
+func (b *bucket) acquire(ctx context.Context, capacity int, refill time.Duration) error {
+	for {
+		b.mu.Lock()
+		b.refillLocked(capacity, refill)
+
+		wait := time.Until(b.nextAvailable)
+		if wait <= 0 && b.tokens > 0 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		if wait <= 0 {
+			wait = refill
+		}
+		b.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (b *bucket) refillLocked(capacity int, refill time.Duration) {
+	if refill <= 0 || b.tokens >= capacity {
+		return
+	}
+
+	elapsed := time.Since(b.lastRefill)
+	add := int(elapsed / refill)
+	if add <= 0 {
+		return
+	}
+
+	b.tokens += add
+	if b.tokens > capacity {
+		b.tokens = capacity
+	}
+	b.lastRefill = b.lastRefill.Add(time.Duration(add) * refill)
+}
+
+// adjust updates the bucket from a response's rate-limit headers: the
+// remaining-token count, and any of Retry-After, X-RateLimit-Reset-After or
+// X-RateLimit-Reset that indicate when the bucket next becomes available.
+func (b *bucket) adjust(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	header := resp.Header
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if remaining, ok := parseInt(header.Get("X-RateLimit-Remaining")); ok && remaining < b.tokens {
+		b.tokens = remaining
+	}
+
+	if wait, ok := retryDelay(header); ok {
+		if next := time.Now().Add(wait); next.After(b.nextAvailable) {
+			b.nextAvailable = next
+		}
+		b.tokens = 0
+		return
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		// no usable hint, fall back to a one-second wait
+		if next := time.Now().Add(time.Second); next.After(b.nextAvailable) {
+			b.nextAvailable = next
+		}
+		b.tokens = 0
+	}
+}
+
+// retryDelay extracts a wait duration from, in order of preference,
+// Retry-After, X-RateLimit-Reset-After (both delta-seconds, fractional
+// allowed) and X-RateLimit-Reset (a Unix timestamp or HTTP-date).
+func retryDelay(header http.Header) (time.Duration, bool) {
+	if d, ok := parseDelay(header.Get("Retry-After")); ok {
+		return d, true
+	}
+	if d, ok := parseDelay(header.Get("X-RateLimit-Reset-After")); ok {
+		return d, true
+	}
+	if v := header.Get("X-RateLimit-Reset"); v != "" {
+		if secs, err := strconv.ParseFloat(v, 64); err == nil {
+			d := time.Until(time.Unix(0, int64(secs*float64(time.Second))))
+			if d < 0 {
+				d = 0
+			}
+			return d, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			d := time.Until(t)
+			if d < 0 {
+				d = 0
+			}
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// parseDelay parses a delta-seconds value (fractional allowed, per
+// Discord-style rate-limit headers) or an HTTP-date, as Retry-After and
+// X-RateLimit-Reset-After may be expressed either way.
+func parseDelay(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseFloat(value, 64); err == nil {
+		if secs < 0 {
+			secs = 0
+		}
+		return time.Duration(secs * float64(time.Second)), true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func parseInt(value string) (int, bool) {
+	if value == "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiter_AcquireBlocksUntilRefill(t *testing.T) {
+	l := New(1, 50*time.Millisecond)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/path", nil)
+
+	if err := l.Acquire(context.Background(), req); err != nil {
+		t.Fatalf("first Acquire: %v", err)
+	}
+
+	start := time.Now()
+	if err := l.Acquire(context.Background(), req); err != nil {
+		t.Fatalf("second Acquire: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+		t.Fatalf("second Acquire returned too early after %v, expected to wait for refill", elapsed)
+	}
+}
+
+func TestLimiter_AcquireRespectsContextCancellation(t *testing.T) {
+	l := New(1, time.Hour)
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/path", nil)
+
+	if err := l.Acquire(context.Background(), req); err != nil {
+		t.Fatalf("first Acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := l.Acquire(ctx, req); err != context.DeadlineExceeded {
+		t.Fatalf("Acquire() = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestLimiter_ReleaseHonorsRetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "0.2")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	l := New(2, time.Millisecond)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err := l.Acquire(context.Background(), req); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+
+	if err := l.Release(context.Background(), req, resp); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+
+	start := time.Now()
+	if err := l.Acquire(context.Background(), req); err != nil {
+		t.Fatalf("Acquire after 429: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("Acquire returned too early after %v, expected to honor Retry-After", elapsed)
+	}
+}
+
+func TestLimiter_WithBucketKeyOverridesHost(t *testing.T) {
+	l := New(1, time.Hour)
+
+	reqA, _ := http.NewRequest(http.MethodGet, "http://host-a.example.com/path", nil)
+	reqB, _ := http.NewRequest(http.MethodGet, "http://host-b.example.com/path", nil)
+
+	ctx := WithBucketKey(context.Background(), "shared")
+
+	if err := l.Acquire(ctx, reqA); err != nil {
+		t.Fatalf("Acquire reqA: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Acquire(ctx, reqB)
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Acquire reqB should have blocked on the shared bucket, got err=%v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
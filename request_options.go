@@ -1,9 +1,18 @@
 package httpclient
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"net/url"
+	"strings"
+
+	"github.com/stn81/httpclient/ratelimit"
 )
 
 // RequestOption defines the request option to customize the request
@@ -45,3 +54,150 @@ func SetQuery(values url.Values) RequestOption {
 		return ctx, nil
 	}
 }
+
+type contextKey int
+
+const (
+	allowRetryNonIdempotentKey contextKey = iota
+)
+
+// AllowRetryNonIdempotent opts the request into retrying even though its
+// method (POST/PATCH) is not idempotent. Without this, RetryClassifier
+// refuses to retry such requests to avoid duplicating side effects.
+func AllowRetryNonIdempotent() RequestOption {
+	return func(ctx context.Context, req *http.Request) (context.Context, error) {
+		return context.WithValue(ctx, allowRetryNonIdempotentKey, true), nil
+	}
+}
+
+// SetRateLimitBucket overrides the rate-limit bucket key a client's
+// ratelimit.Limiter would otherwise derive from the request's host.
+func SetRateLimitBucket(key string) RequestOption {
+	return func(ctx context.Context, req *http.Request) (context.Context, error) {
+		return ratelimit.WithBucketKey(ctx, key), nil
+	}
+}
+
+// SetForm sets the Content-Type to `application/x-www-form-urlencoded` and
+// the request body to the url-encoded form values.
+func SetForm(values url.Values) RequestOption {
+	encoded := []byte(values.Encode())
+
+	return func(ctx context.Context, req *http.Request) (context.Context, error) {
+		setBody(req, encoded)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return ctx, nil
+	}
+}
+
+// MultipartFile is one file part of a SetMultipart request body. Open is
+// called each time the body is built: once per attempt (the initial send
+// and, if the request is retried, again for each retry), and again
+// whenever the request's GetBody is invoked (e.g. by a capture hook or on
+// a redirect), so it must return a fresh io.Reader
+// each time rather than handing out one that a previous attempt already
+// consumed — e.g. re-open the underlying file instead of capturing an
+// *os.File opened up front.
+type MultipartFile struct {
+	Filename    string
+	ContentType string
+	Open        func() (io.Reader, error)
+}
+
+// SetMultipart builds a multipart/form-data body out of fields and files
+// and sets it, along with the matching Content-Type (including boundary),
+// as the request body.
+//
+// The body is (re-)encoded from scratch on every attempt via each file's
+// Open, not built once up front and cached: for large uploads, holding
+// the fully-encoded body in memory for the lifetime of the RequestOption
+// would work against the memory-conscious streaming this package favors
+// elsewhere (see DoStream). The trade-off is that a retried request
+// re-encodes the whole body, including any small fields, rather than
+// replaying cached bytes.
+func SetMultipart(fields map[string]string, files map[string]MultipartFile) RequestOption {
+	build := func(boundary string) ([]byte, string, error) {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		if err := writer.SetBoundary(boundary); err != nil {
+			return nil, "", err
+		}
+
+		for name, value := range fields {
+			if err := writer.WriteField(name, value); err != nil {
+				return nil, "", err
+			}
+		}
+
+		for field, file := range files {
+			part, err := createFormFile(writer, field, file)
+			if err != nil {
+				return nil, "", err
+			}
+			reader, err := file.Open()
+			if err != nil {
+				return nil, "", err
+			}
+			if _, err := io.Copy(part, reader); err != nil {
+				return nil, "", err
+			}
+		}
+
+		if err := writer.Close(); err != nil {
+			return nil, "", err
+		}
+
+		return buf.Bytes(), writer.FormDataContentType(), nil
+	}
+
+	return func(ctx context.Context, req *http.Request) (context.Context, error) {
+		// One boundary per request, so a body rebuilt by GetBody still
+		// matches the Content-Type header set below.
+		boundary := multipart.NewWriter(nil).Boundary()
+		data, contentType, err := build(boundary)
+		if err != nil {
+			return ctx, err
+		}
+
+		req.ContentLength = int64(len(data))
+		req.Body = ioutil.NopCloser(bytes.NewReader(data))
+		req.GetBody = func() (io.ReadCloser, error) {
+			data, _, err := build(boundary)
+			if err != nil {
+				return nil, err
+			}
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		}
+		req.Header.Set("Content-Type", contentType)
+		return ctx, nil
+	}
+}
+
+var multipartQuoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func createFormFile(writer *multipart.Writer, field string, file MultipartFile) (io.Writer, error) {
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(
+		`form-data; name="%s"; filename="%s"`,
+		multipartQuoteEscaper.Replace(field), multipartQuoteEscaper.Replace(file.Filename),
+	))
+	header.Set("Content-Type", contentType)
+
+	return writer.CreatePart(header)
+}
+
+// setBody installs data as req's body (and GetBody, so retries and
+// redirects replay the same bytes instead of an already-consumed reader).
+func setBody(req *http.Request, data []byte) {
+	req.ContentLength = int64(len(data))
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
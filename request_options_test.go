@@ -0,0 +1,147 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestSetForm(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-www-form-urlencoded" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != "name=gopher" {
+			t.Errorf("body = %q, want %q", body, "name=gopher")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := New(zap.NewNop())
+	values := url.Values{"name": []string{"gopher"}}
+
+	if _, err := client.Post(context.Background(), server.URL, "", SetForm(values)); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+}
+
+func TestSetMultipart(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("ParseMultipartForm: %v", err)
+		}
+
+		if got := r.FormValue("field"); got != "value" {
+			t.Errorf("field = %q, want %q", got, "value")
+		}
+
+		file, header, err := r.FormFile("upload")
+		if err != nil {
+			t.Fatalf("FormFile: %v", err)
+		}
+		defer file.Close()
+
+		if header.Filename != "hello.txt" {
+			t.Errorf("Filename = %q, want %q", header.Filename, "hello.txt")
+		}
+		if ct := header.Header.Get("Content-Type"); ct != "text/plain" {
+			t.Errorf("file Content-Type = %q, want %q", ct, "text/plain")
+		}
+
+		data, _ := ioutil.ReadAll(file)
+		if string(data) != "hello world" {
+			t.Errorf("file contents = %q", data)
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := New(zap.NewNop())
+
+	opt := SetMultipart(
+		map[string]string{"field": "value"},
+		map[string]MultipartFile{
+			"upload": {
+				Filename:    "hello.txt",
+				ContentType: "text/plain",
+				Open: func() (io.Reader, error) {
+					return strings.NewReader("hello world"), nil
+				},
+			},
+		},
+	)
+
+	if _, err := client.Post(context.Background(), server.URL, "", opt); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+}
+
+func TestSetMultipart_RetriesReplayBody(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		mr, err := r.MultipartReader()
+		if err != nil {
+			t.Fatalf("MultipartReader: %v", err)
+		}
+
+		var part *multipart.Part
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			if p.FormName() == "upload" {
+				part = p
+				break
+			}
+		}
+		if part == nil {
+			t.Fatal("upload part not found")
+		}
+
+		data, _ := ioutil.ReadAll(part)
+		if string(data) != "retry me" {
+			t.Errorf("file contents = %q, want %q", data, "retry me")
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := New(zap.NewNop())
+	client.SetRetry([]time.Duration{0})
+
+	opt := SetMultipart(nil, map[string]MultipartFile{
+		"upload": {
+			Filename: "hello.txt",
+			Open: func() (io.Reader, error) {
+				return strings.NewReader("retry me"), nil
+			},
+		},
+	})
+
+	if _, err := client.Post(context.Background(), server.URL, "", opt, AllowRetryNonIdempotent()); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("attempts = %d, want 2", attempts)
+	}
+}
@@ -0,0 +1,85 @@
+package httpclient
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Response wraps the *http.Response returned by DoStream. Body is already
+// unwrapped from a gzip, deflate or br Content-Encoding the server applied
+// (any other encoding is passed through as is), so callers can read it
+// directly instead of buffering the whole thing into memory.
+type Response struct {
+	*http.Response
+	Body io.ReadCloser
+
+	// allowNonIdempotent carries the request's AllowRetryNonIdempotent
+	// opt-in past doStream, so Do/JSONClient.Do/XMLClient.Do can honor it
+	// when wrapping a body-read/decode failure for RetryClassifier.
+	allowNonIdempotent bool
+}
+
+// JSON decodes the response body as JSON into v, then closes the body.
+func (r *Response) JSON(v interface{}) error {
+	defer r.Body.Close()
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+// XML decodes the response body as XML into v, then closes the body.
+func (r *Response) XML(v interface{}) error {
+	defer r.Body.Close()
+	return xml.NewDecoder(r.Body).Decode(v)
+}
+
+// Bytes reads the entire response body into memory, then closes the body.
+func (r *Response) Bytes() ([]byte, error) {
+	defer r.Body.Close()
+	return ioutil.ReadAll(r.Body)
+}
+
+// multiCloser closes every wrapped closer in order, keeping the first
+// error encountered. It lets a decompressing reader (which only unwinds
+// its own state on Close) also release the underlying response body.
+type multiCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (c *multiCloser) Close() error {
+	var err error
+	for _, closer := range c.closers {
+		if cerr := closer.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// decodeBody wraps resp.Body with a decompressing reader according to its
+// Content-Encoding, for the case a server sends compressed data even
+// without the client advertising support for it via Accept-Encoding.
+func decodeBody(resp *http.Response) (io.ReadCloser, error) {
+	switch resp.Header.Get("Content-Encoding") {
+	case "gzip":
+		reader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &multiCloser{Reader: reader, closers: []io.Closer{reader, resp.Body}}, nil
+	case "deflate":
+		reader := flate.NewReader(resp.Body)
+		return &multiCloser{Reader: reader, closers: []io.Closer{reader, resp.Body}}, nil
+	case "br":
+		reader := brotli.NewReader(resp.Body)
+		return &multiCloser{Reader: reader, closers: []io.Closer{resp.Body}}, nil
+	default:
+		return resp.Body, nil
+	}
+}
@@ -1,14 +1,45 @@
 package httpclient
 
 import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
 	"github.com/eapache/go-resiliency/retrier"
 )
 
-// HTTP2RetriableError defines the errors that considered retriable
+// HTTP2RetriableError defines the HTTP/2 stream-level error tokens that are
+// considered retriable when they appear in an error's message.
 var HTTP2RetriableError = []string{
 	"CONNECT_ERROR",
 	"PROTOCOL_ERROR",
 	"STREAM_CLOSED",
+	"GOAWAY",
+	"RST_STREAM",
+}
+
+// retriableStatusCode defines the HTTP status codes that are safe to retry.
+var retriableStatusCode = map[int]bool{
+	http.StatusRequestTimeout:      true, // 408
+	425:                            true, // Too Early
+	http.StatusTooManyRequests:     true, // 429
+	http.StatusInternalServerError: true, // 500
+	http.StatusBadGateway:          true, // 502
+	http.StatusServiceUnavailable:  true, // 503
+	http.StatusGatewayTimeout:      true, // 504
+}
+
+// nonIdempotentMethod defines the HTTP methods that must not be retried
+// unless the caller opts in via AllowRetryNonIdempotent.
+var nonIdempotentMethod = map[string]bool{
+	http.MethodPost:  true,
+	http.MethodPatch: true,
 }
 
 // DefaultRetryClassifier is the default retry classifier
@@ -23,5 +54,125 @@ func (r *RetryClassifier) Classify(err error) retrier.Action {
 		return retrier.Succeed
 	}
 
-	return retrier.Retry
+	method := ""
+	allowNonIdempotent := false
+
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		method = reqErr.method
+		allowNonIdempotent = reqErr.allowNonIdempotent
+		err = reqErr.err
+	}
+
+	var breakerErr *BreakerOpenError
+	if errors.As(err, &breakerErr) {
+		// The breaker is already tracking failures; retrying immediately
+		// would just hammer it while it's open.
+		return retrier.Fail
+	}
+
+	if nonIdempotentMethod[method] && !allowNonIdempotent {
+		return retrier.Fail
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return retrier.Fail
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		if retriableStatusCode[httpErr.StatusCode] {
+			return retrier.Retry
+		}
+		return retrier.Fail
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		// nolint: staticcheck
+		if netErr.Timeout() || netErr.Temporary() {
+			return retrier.Retry
+		}
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return retrier.Retry
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return retrier.Retry
+	}
+
+	msg := err.Error()
+	for _, token := range HTTP2RetriableError {
+		if strings.Contains(msg, token) {
+			return retrier.Retry
+		}
+	}
+
+	return retrier.Fail
+}
+
+// requestError wraps an error from a single request attempt with the
+// metadata RetryClassifier needs to make a retry decision that depends on
+// the request itself, since retrier.Classifier only sees the error.
+type requestError struct {
+	err                error
+	method             string
+	allowNonIdempotent bool
+}
+
+// Error implements the error interface
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap allows errors.Is/errors.As to see through to the underlying error
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+// wrapRequestError wraps a non-nil err with the method/allowNonIdempotent
+// metadata RetryClassifier needs to decide whether to retry a
+// non-idempotent request. It is for errors that happen after doStream's
+// own round trip already succeeded — reading or decoding the response
+// body in Do, JSONClient.Do, and XMLClient.Do — which doStream's own
+// requestError wrapping never sees, since doStream has already returned
+// by then. allowNonIdempotent should come from the Response's own field,
+// since it reflects AllowRetryNonIdempotent() as resolved by doStream for
+// this request, not the caller's own ctx (doStream resolves RequestOptions
+// against a ctx derived from, but not propagated back to, the caller's).
+func wrapRequestError(err error, method string, allowNonIdempotent bool) error {
+	if err == nil {
+		return nil
+	}
+	return &requestError{err: err, method: method, allowNonIdempotent: allowNonIdempotent}
+}
+
+// RetryAfter extracts the retry delay indicated by a 429/503 response's
+// Retry-After header, if any. It understands both the delta-seconds and
+// HTTP-date forms of the header.
+func RetryAfter(err error) (time.Duration, bool) {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Header == nil {
+		return 0, false
+	}
+
+	value := httpErr.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if secs, parseErr := strconv.Atoi(value); parseErr == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, parseErr := http.ParseTime(value); parseErr == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+
+	return 0, false
 }
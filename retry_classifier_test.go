@@ -0,0 +1,128 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/eapache/go-resiliency/retrier"
+)
+
+func TestRetryClassifier_Classify(t *testing.T) {
+	wrap := func(err error, method string, allowNonIdempotent bool) error {
+		return &requestError{err: err, method: method, allowNonIdempotent: allowNonIdempotent}
+	}
+
+	cases := []struct {
+		name string
+		err  error
+		want retrier.Action
+	}{
+		{
+			name: "nil error succeeds",
+			err:  nil,
+			want: retrier.Succeed,
+		},
+		{
+			name: "context canceled fails",
+			err:  wrap(context.Canceled, http.MethodGet, false),
+			want: retrier.Fail,
+		},
+		{
+			name: "context deadline exceeded fails",
+			err:  wrap(context.DeadlineExceeded, http.MethodGet, false),
+			want: retrier.Fail,
+		},
+		{
+			name: "retriable status code retries",
+			err:  wrap(&HTTPError{StatusCode: http.StatusServiceUnavailable}, http.MethodGet, false),
+			want: retrier.Retry,
+		},
+		{
+			name: "non-retriable status code fails",
+			err:  wrap(&HTTPError{StatusCode: http.StatusNotFound}, http.MethodGet, false),
+			want: retrier.Fail,
+		},
+		{
+			name: "unexpected EOF retries",
+			err:  wrap(io.ErrUnexpectedEOF, http.MethodGet, false),
+			want: retrier.Retry,
+		},
+		{
+			name: "econnreset retries",
+			err:  wrap(syscall.ECONNRESET, http.MethodGet, false),
+			want: retrier.Retry,
+		},
+		{
+			name: "econnrefused retries",
+			err:  wrap(syscall.ECONNREFUSED, http.MethodGet, false),
+			want: retrier.Retry,
+		},
+		{
+			name: "http2 goaway token retries",
+			err:  wrap(errors.New("http2: server sent GOAWAY"), http.MethodGet, false),
+			want: retrier.Retry,
+		},
+		{
+			name: "non-idempotent POST fails by default",
+			err:  wrap(&HTTPError{StatusCode: http.StatusServiceUnavailable}, http.MethodPost, false),
+			want: retrier.Fail,
+		},
+		{
+			name: "non-idempotent POST retries when opted in",
+			err:  wrap(&HTTPError{StatusCode: http.StatusServiceUnavailable}, http.MethodPost, true),
+			want: retrier.Retry,
+		},
+		{
+			name: "unrecognized error fails",
+			err:  wrap(errors.New("something unexpected"), http.MethodGet, false),
+			want: retrier.Fail,
+		},
+		{
+			name: "open breaker fails",
+			err:  wrap(&BreakerOpenError{Host: "example.com"}, http.MethodGet, false),
+			want: retrier.Fail,
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			got := DefaultRetryClassifier.Classify(c.err)
+			if got != c.want {
+				t.Errorf("Classify(%v) = %v, want %v", c.err, got, c.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Run("delta seconds", func(t *testing.T) {
+		err := &requestError{err: &HTTPError{
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{"Retry-After": []string{"2"}},
+		}}
+
+		d, ok := RetryAfter(err)
+		if !ok || d.Seconds() != 2 {
+			t.Fatalf("RetryAfter() = %v, %v, want 2s, true", d, ok)
+		}
+	})
+
+	t.Run("missing header", func(t *testing.T) {
+		err := &requestError{err: &HTTPError{StatusCode: http.StatusTooManyRequests}}
+
+		if _, ok := RetryAfter(err); ok {
+			t.Fatal("RetryAfter() should report false when header is absent")
+		}
+	})
+
+	t.Run("non HTTPError", func(t *testing.T) {
+		if _, ok := RetryAfter(errors.New("boom")); ok {
+			t.Fatal("RetryAfter() should report false for non-HTTPError errors")
+		}
+	})
+}
@@ -1,8 +1,11 @@
 package httpclient
 
 import (
+	"bytes"
 	"context"
 	"encoding/xml"
+	"io"
+	"io/ioutil"
 
 	"go.uber.org/zap"
 )
@@ -53,13 +56,10 @@ func (client *XMLClient) Delete(ctx context.Context, url string, body, result in
 	return client.Do(ctx, "DELETE", url, body, result, reqOpts...)
 }
 
-// Do sends a custom METHOD request
+// Do sends a custom METHOD request, decoding the response body as XML
+// incrementally via DoStream instead of buffering it into a string first.
 func (client *XMLClient) Do(ctx context.Context, method, url string, body, result interface{}, reqOpts ...RequestOption) error {
-	var (
-		bodyData  []byte
-		resultStr string
-		err       error
-	)
+	var bodyData []byte
 
 	if body != nil {
 		switch bodyValue := body.(type) {
@@ -68,6 +68,7 @@ func (client *XMLClient) Do(ctx context.Context, method, url string, body, resul
 		case []byte:
 			bodyData = bodyValue
 		default:
+			var err error
 			if bodyData, err = xml.Marshal(body); err != nil {
 				client.logger.Error("marshal request body", zap.Error(err))
 				return err
@@ -77,15 +78,25 @@ func (client *XMLClient) Do(ctx context.Context, method, url string, body, resul
 
 	reqOpts = append([]RequestOption{SetTypeXML()}, reqOpts...)
 
-	if resultStr, err = client.Client.Do(ctx, method, url, string(bodyData), reqOpts...); err != nil {
-		return err
-	}
+	return client.runRetriable(ctx, func() error {
+		resp, err := client.Client.DoStream(ctx, method, url, bytes.NewReader(bodyData), reqOpts...)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if result == nil {
+			_, err := io.Copy(ioutil.Discard, resp.Body)
+			return wrapRequestError(err, method, resp.allowNonIdempotent)
+		}
 
-	if result != nil && resultStr != "" {
-		if err = xml.Unmarshal([]byte(resultStr), result); err != nil {
+		if err := xml.NewDecoder(resp.Body).Decode(result); err != nil {
+			if err == io.EOF {
+				return nil
+			}
 			client.logger.Error("unmarshal response body", zap.Error(err))
-			return err
+			return wrapRequestError(err, method, resp.allowNonIdempotent)
 		}
-	}
-	return nil
+		return nil
+	})
 }